@@ -0,0 +1,272 @@
+// Package auth provides a tracker-agnostic credential abstraction used by
+// the issue tracker integrations under pkg/reporting/issues (Jira today,
+// GitHub/GitLab going forward) so that each integration does not have to
+// reimplement its own authentication handling.
+package auth
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/dghubble/oauth1"
+)
+
+// Kind identifies the type of credential used to authenticate against an
+// issue tracker.
+type Kind string
+
+const (
+	// KindLoginPassword authenticates with a plain username/password pair.
+	KindLoginPassword Kind = "login-password"
+	// KindAPIToken authenticates with an email + API token pair, as used by
+	// Atlassian Cloud.
+	KindAPIToken Kind = "api-token"
+	// KindPersonalAccessToken authenticates with a bearer PAT, as used by
+	// self-hosted Jira/GitHub/GitLab instances.
+	KindPersonalAccessToken Kind = "personal-access-token"
+	// KindOAuth1 authenticates with a 3-legged OAuth1 (RSA-SHA1 signed)
+	// credential, as required by Atlassian Cloud/Server when basic auth is
+	// disabled by SSO policy.
+	KindOAuth1 Kind = "oauth1"
+	// KindTokenFile authenticates with a personal access token read from an
+	// on-disk keyring/secret path rather than inline configuration.
+	KindTokenFile Kind = "token-file"
+)
+
+// Credential is implemented by every supported authentication mechanism.
+type Credential interface {
+	// Kind returns the kind of credential.
+	Kind() Kind
+	// HTTPClient returns a client pre-configured to authenticate requests.
+	HTTPClient() (*http.Client, error)
+	// Validate returns an error if the credential is not usable as configured.
+	Validate() error
+}
+
+// Options holds the configuration for every supported credential kind. Only
+// the fields relevant to Kind need to be set.
+type Options struct {
+	// Kind selects which credential implementation is constructed.
+	Kind Kind `yaml:"kind"`
+	// Username is used by KindLoginPassword.
+	Username string `yaml:"username"`
+	// Password is used by KindLoginPassword.
+	Password string `yaml:"password"`
+	// Email is used by KindAPIToken.
+	Email string `yaml:"email"`
+	// Token is the API token (KindAPIToken) or the personal access token
+	// (KindPersonalAccessToken). KindOAuth1 reads its access token from
+	// OAuth1 instead.
+	Token string `yaml:"token"`
+	// OAuth1 holds the parameters required by KindOAuth1.
+	OAuth1 *OAuth1Options `yaml:"oauth1"`
+	// TokenFilePath is the path to a file containing a personal access
+	// token, used by KindTokenFile.
+	TokenFilePath string `yaml:"token-file-path"`
+}
+
+// OAuth1Options holds the 3-legged OAuth1 parameters required to sign
+// requests on behalf of an already-authorized user.
+type OAuth1Options struct {
+	// ConsumerKey is the OAuth1 consumer key registered with the tracker.
+	ConsumerKey string `yaml:"consumer-key"`
+	// PrivateKeyPEM is the PEM encoded RSA private key matching the public
+	// key registered with the tracker, used to RSA-SHA1 sign requests.
+	PrivateKeyPEM string `yaml:"private-key-pem"`
+	// AccessToken is the access token obtained from the 3-legged handshake.
+	AccessToken string `yaml:"access-token"`
+	// AccessTokenSecret is the access token secret obtained from the
+	// 3-legged handshake.
+	AccessTokenSecret string `yaml:"access-token-secret"`
+}
+
+// New returns the Credential matching options.Kind.
+func New(options *Options) (Credential, error) {
+	var credential Credential
+	switch options.Kind {
+	case KindAPIToken:
+		credential = &APIToken{Email: options.Email, Token: options.Token}
+	case KindPersonalAccessToken:
+		credential = &PersonalAccessToken{Token: options.Token}
+	case KindOAuth1:
+		credential = &OAuth1{options: options.OAuth1}
+	case KindTokenFile:
+		credential = &TokenFile{Path: options.TokenFilePath}
+	case KindLoginPassword, "":
+		credential = &LoginPassword{Username: options.Username, Password: options.Password}
+	default:
+		return nil, fmt.Errorf("unsupported credential kind: %s", options.Kind)
+	}
+	if err := credential.Validate(); err != nil {
+		return nil, err
+	}
+	return credential, nil
+}
+
+// LoginPassword authenticates with HTTP basic auth using a username and
+// password.
+type LoginPassword struct {
+	Username string
+	Password string
+}
+
+func (c *LoginPassword) Kind() Kind { return KindLoginPassword }
+
+func (c *LoginPassword) Validate() error {
+	if c.Username == "" || c.Password == "" {
+		return fmt.Errorf("username and password are required for login-password auth")
+	}
+	return nil
+}
+
+func (c *LoginPassword) HTTPClient() (*http.Client, error) {
+	return &http.Client{Transport: &basicAuthTransport{username: c.Username, password: c.Password}}, nil
+}
+
+// APIToken authenticates with HTTP basic auth using an account email and an
+// API token, as required by Atlassian Cloud.
+type APIToken struct {
+	Email string
+	Token string
+}
+
+func (c *APIToken) Kind() Kind { return KindAPIToken }
+
+func (c *APIToken) Validate() error {
+	if c.Email == "" || c.Token == "" {
+		return fmt.Errorf("email and token are required for api-token auth")
+	}
+	return nil
+}
+
+func (c *APIToken) HTTPClient() (*http.Client, error) {
+	return &http.Client{Transport: &basicAuthTransport{username: c.Email, password: c.Token}}, nil
+}
+
+// PersonalAccessToken authenticates with a bearer token, as required by
+// self-hosted Jira Server/Data Center instances whose SSO policy forbids
+// basic auth.
+type PersonalAccessToken struct {
+	Token string
+}
+
+func (c *PersonalAccessToken) Kind() Kind { return KindPersonalAccessToken }
+
+func (c *PersonalAccessToken) Validate() error {
+	if c.Token == "" {
+		return fmt.Errorf("token is required for personal-access-token auth")
+	}
+	return nil
+}
+
+func (c *PersonalAccessToken) HTTPClient() (*http.Client, error) {
+	return &http.Client{Transport: &bearerAuthTransport{token: c.Token}}, nil
+}
+
+// TokenFile authenticates with a bearer token read from an on-disk path,
+// e.g. a path managed by an external keyring/secrets manager.
+type TokenFile struct {
+	Path string
+}
+
+func (c *TokenFile) Kind() Kind { return KindTokenFile }
+
+func (c *TokenFile) Validate() error {
+	if c.Path == "" {
+		return fmt.Errorf("token-file-path is required for token-file auth")
+	}
+	return nil
+}
+
+func (c *TokenFile) HTTPClient() (*http.Client, error) {
+	data, err := ioutil.ReadFile(c.Path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read token file: %s", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return nil, fmt.Errorf("token file %s is empty", c.Path)
+	}
+	return &http.Client{Transport: &bearerAuthTransport{token: token}}, nil
+}
+
+// OAuth1 authenticates with a 3-legged, RSA-SHA1 signed OAuth1 credential,
+// as required by Atlassian Cloud/Server for SSO-protected instances.
+type OAuth1 struct {
+	options *OAuth1Options
+}
+
+func (c *OAuth1) Kind() Kind { return KindOAuth1 }
+
+func (c *OAuth1) Validate() error {
+	if c.options == nil {
+		return fmt.Errorf("oauth1 options are required for oauth1 auth")
+	}
+	if c.options.ConsumerKey == "" || c.options.PrivateKeyPEM == "" || c.options.AccessToken == "" || c.options.AccessTokenSecret == "" {
+		return fmt.Errorf("consumer-key, private-key-pem, access-token and access-token-secret are required for oauth1 auth")
+	}
+	return nil
+}
+
+func (c *OAuth1) HTTPClient() (*http.Client, error) {
+	block, _ := pem.Decode([]byte(c.options.PrivateKeyPEM))
+	if block == nil {
+		return nil, fmt.Errorf("could not decode private-key-pem")
+	}
+	privateKey, err := parseRSAPrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse private-key-pem: %s", err)
+	}
+
+	config := oauth1.NewConfig(c.options.ConsumerKey, "")
+	config.Signer = &oauth1.RSASigner{PrivateKey: privateKey}
+	token := oauth1.NewToken(c.options.AccessToken, c.options.AccessTokenSecret)
+	return config.Client(oauth1.NoContext, token), nil
+}
+
+// parseRSAPrivateKey parses an RSA private key encoded as PKCS1 ("RSA
+// PRIVATE KEY") or PKCS8 ("PRIVATE KEY"), the two formats commonly produced
+// by openssl and Atlassian's OAuth1 app-link setup docs.
+func parseRSAPrivateKey(der []byte) (*rsa.PrivateKey, error) {
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is not an RSA key")
+	}
+	return rsaKey, nil
+}
+
+// basicAuthTransport sets the HTTP basic auth header on every request.
+type basicAuthTransport struct {
+	username string
+	password string
+}
+
+func (t *basicAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	cloned := req.Clone(req.Context())
+	cloned.SetBasicAuth(t.username, t.password)
+	return http.DefaultTransport.RoundTrip(cloned)
+}
+
+// bearerAuthTransport sets the HTTP bearer Authorization header on every
+// request.
+type bearerAuthTransport struct {
+	token string
+}
+
+func (t *bearerAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	cloned := req.Clone(req.Context())
+	cloned.Header.Set("Authorization", "Bearer "+t.token)
+	return http.DefaultTransport.RoundTrip(cloned)
+}
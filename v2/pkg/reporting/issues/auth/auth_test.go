@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewSelectsCredentialByKind(t *testing.T) {
+	tests := []struct {
+		name    string
+		options *Options
+		wantErr bool
+		kind    Kind
+	}{
+		{"login-password", &Options{Kind: KindLoginPassword, Username: "u", Password: "p"}, false, KindLoginPassword},
+		{"login-password missing password", &Options{Kind: KindLoginPassword, Username: "u"}, true, ""},
+		{"api-token", &Options{Kind: KindAPIToken, Email: "e@example.com", Token: "t"}, false, KindAPIToken},
+		{"api-token missing email", &Options{Kind: KindAPIToken, Token: "t"}, true, ""},
+		{"personal-access-token", &Options{Kind: KindPersonalAccessToken, Token: "t"}, false, KindPersonalAccessToken},
+		{"personal-access-token missing token", &Options{Kind: KindPersonalAccessToken}, true, ""},
+		{"token-file", &Options{Kind: KindTokenFile, TokenFilePath: "/tmp/token"}, false, KindTokenFile},
+		{"token-file missing path", &Options{Kind: KindTokenFile}, true, ""},
+		{"default kind falls back to login-password", &Options{Username: "u", Password: "p"}, false, KindLoginPassword},
+		{"unknown kind", &Options{Kind: "bogus"}, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			credential, err := New(tt.options)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if credential.Kind() != tt.kind {
+				t.Errorf("Kind() = %s, want %s", credential.Kind(), tt.kind)
+			}
+		})
+	}
+}
+
+func TestBasicAuthTransportSetsCredentials(t *testing.T) {
+	var gotUser, gotPass string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUser, gotPass, _ = r.BasicAuth()
+	}))
+	defer server.Close()
+
+	credential, err := New(&Options{Kind: KindAPIToken, Email: "user@example.com", Token: "tok"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	client, err := credential.HTTPClient()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := client.Get(server.URL); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if gotUser != "user@example.com" || gotPass != "tok" {
+		t.Errorf("got basic auth (%s, %s), want (user@example.com, tok)", gotUser, gotPass)
+	}
+}
+
+func TestBearerAuthTransportSetsCredentials(t *testing.T) {
+	var gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	credential, err := New(&Options{Kind: KindPersonalAccessToken, Token: "tok"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	client, err := credential.HTTPClient()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := client.Get(server.URL); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want := "Bearer tok"; gotAuth != want {
+		t.Errorf("Authorization header = %q, want %q", gotAuth, want)
+	}
+}
+
+func TestParseRSAPrivateKeySupportsPKCS1AndPKCS8(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	pkcs1 := x509.MarshalPKCS1PrivateKey(key)
+	if _, err := parseRSAPrivateKey(pkcs1); err != nil {
+		t.Errorf("could not parse PKCS1 key: %s", err)
+	}
+
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := parseRSAPrivateKey(pkcs8); err != nil {
+		t.Errorf("could not parse PKCS8 key: %s", err)
+	}
+}
+
+func TestOAuth1HTTPClientRejectsInvalidPEM(t *testing.T) {
+	credential := &OAuth1{options: &OAuth1Options{
+		ConsumerKey:       "key",
+		PrivateKeyPEM:     "not a pem block",
+		AccessToken:       "token",
+		AccessTokenSecret: "secret",
+	}}
+	if _, err := credential.HTTPClient(); err == nil {
+		t.Error("expected an error for an invalid PEM block, got none")
+	}
+}
+
+// ensure generated test keys round-trip through pem encoding the same way a
+// user-supplied private-key-pem value would.
+func TestParseRSAPrivateKeyFromPEM(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	block := &pem.Block{Type: "PRIVATE KEY", Bytes: mustMarshalPKCS8(t, key)}
+	der := pem.EncodeToMemory(block)
+
+	decoded, _ := pem.Decode(der)
+	if decoded == nil {
+		t.Fatal("could not decode pem block")
+	}
+	if _, err := parseRSAPrivateKey(decoded.Bytes); err != nil {
+		t.Errorf("could not parse pem-decoded PKCS8 key: %s", err)
+	}
+}
+
+func mustMarshalPKCS8(t *testing.T, key *rsa.PrivateKey) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return der
+}
@@ -0,0 +1,57 @@
+package issues
+
+import (
+	"context"
+
+	"github.com/projectdiscovery/nuclei/v2/pkg/output"
+)
+
+// IssueState represents the lifecycle state of a previously created tracker
+// issue, as reported back by an Importer.
+type IssueState string
+
+const (
+	// IssueStateOpen means the issue is still open and the finding should
+	// keep being reported/updated on subsequent scans.
+	IssueStateOpen IssueState = "open"
+	// IssueStateDone means the issue was resolved/fixed and the finding
+	// should be suppressed until it reoccurs.
+	IssueStateDone IssueState = "done"
+	// IssueStateWontFix means the issue was explicitly accepted/declined
+	// and the finding should be suppressed.
+	IssueStateWontFix IssueState = "wontfix"
+)
+
+// Result carries the outcome of a successful CreateIssue call, so that
+// callers can later dedupe against or update what was created.
+type Result struct {
+	// IssueID is the tracker-assigned identifier of the created/updated issue.
+	IssueID string
+	// AttachmentIDs holds the identifiers of any artifacts (request,
+	// response, extracted results, ...) uploaded as issue attachments rather
+	// than inlined into the issue description.
+	AttachmentIDs []string
+}
+
+// Tracker is implemented by every issue tracker integration capable of
+// creating issues for nuclei findings.
+type Tracker interface {
+	CreateIssue(event *output.ResultEvent) (*Result, error)
+}
+
+// Importer is implemented by tracker integrations that can sync previously
+// created issue state back from the tracker, keyed by the fingerprint the
+// integration stored on the issue when it was created. This allows findings
+// whose issue was resolved or declined in the tracker to be suppressed, and
+// findings whose issue was reopened to resurface, without re-triaging on
+// every scan.
+//
+// Importer is a building block: nothing in pkg/reporting calls it yet, since
+// there is no scan-loop/orchestrator in this tree to suppress or reopen
+// findings from its result. A caller wanting the full round trip needs to
+// call SyncState before a scan and skip/reopen matches based on its result.
+type Importer interface {
+	// SyncState returns the current IssueState of every tracker-managed
+	// issue, keyed by the fingerprint it was created with.
+	SyncState(ctx context.Context) (map[string]IssueState, error)
+}
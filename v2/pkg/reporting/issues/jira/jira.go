@@ -2,16 +2,53 @@ package jira
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"strings"
 
 	jira "github.com/andygrunwald/go-jira"
 	"github.com/projectdiscovery/nuclei/v2/pkg/output"
+	"github.com/projectdiscovery/nuclei/v2/pkg/reporting/issues"
+	"github.com/projectdiscovery/nuclei/v2/pkg/reporting/issues/auth"
 	"github.com/projectdiscovery/nuclei/v2/pkg/reporting/issues/format"
 	"github.com/projectdiscovery/nuclei/v2/pkg/types"
+	"github.com/trivago/tgo/tcontainer"
 )
 
+// fingerprintLabelPrefix prefixes the label nuclei attaches to every issue
+// it creates, carrying the fingerprint (template-id + host + matcher) of the
+// finding it tracks.
+const fingerprintLabelPrefix = "nuclei-fingerprint-"
+
+// trackedLabel is a fixed label attached to every issue nuclei creates, used
+// to scope the SyncState search. Unlike fingerprintLabelPrefix, which embeds
+// a different value per issue, "labels" only supports equality-style JQL
+// operators (=, in, is), so a single well-known label is what search is
+// actually run against; the fingerprint itself is then read back off the
+// issue's labels.
+const trackedLabel = "nuclei-managed"
+
+// doneStatusCategory and wontFixResolution are the Jira status
+// category/resolution names used to classify a synced issue.
+const (
+	doneStatusCategory = "done"
+	wontFixResolution  = "Won't Fix"
+)
+
+// defaultSearchJQLTemplate is the default JQL used to look for an already
+// existing issue for a finding before a new one is created. %s placeholders
+// are filled in with the project name, the template-id+host summary fragment
+// and the comma separated status filter, in that order.
+const defaultSearchJQLTemplate = `project = "%s" AND summary ~ "%s" AND status not in (%s)`
+
+// defaultStatusFilter is the list of statuses that mark an issue as closed.
+// Closed issues are excluded from the dedupe search, so a new occurrence of
+// their finding creates a new issue; the closed issue itself is not reopened.
+var defaultStatusFilter = []string{"Done"}
+
 // Integration is a client for a issue tracker integration
 type Integration struct {
 	jira    *jira.Client
@@ -28,55 +65,449 @@ type Options struct {
 	Email string `yaml:"email"`
 	// Token is the token for jira instance.
 	Token string `yaml:"token"`
+	// Auth is the credential used to authenticate against the jira instance.
+	// If unset, Email/Token are used to build a KindAPIToken credential.
+	Auth *auth.Options `yaml:"auth"`
 	// ProjectName is the name of the project.
 	ProjectName string `yaml:"project-name"`
 	// IssueType is the name of the created issue type
 	IssueType string `yaml:"issue-type"`
+	// UpdateExisting updates a still-open matching issue instead of creating a duplicate.
+	UpdateExisting bool `yaml:"update-existing"`
+	// SearchJQLTemplate is a custom dedupe JQL query, defaults to defaultSearchJQLTemplate.
+	SearchJQLTemplate string `yaml:"search-jql-template"`
+	// StatusFilter is the list of statuses considered closed. Defaults to ["Done"].
+	StatusFilter []string `yaml:"status-filter"`
+	// CustomFields maps custom field ids (e.g. "customfield_10000") to their values.
+	CustomFields map[string]interface{} `yaml:"custom-fields"`
+	// Labels is a list of labels attached to the created issue.
+	Labels []string `yaml:"labels"`
+	// Components is a list of component names attached to the created issue.
+	Components []string `yaml:"components"`
+	// Priority is the issue priority used when SeverityMapping has no entry.
+	Priority string `yaml:"priority"`
+	// SeverityMapping maps a nuclei severity to a Jira priority name.
+	SeverityMapping map[string]string `yaml:"severity-mapping"`
+	// IssueTypeMapping maps a nuclei severity to a Jira issue type name.
+	IssueTypeMapping map[string]string `yaml:"issue-type-mapping"`
+	// AttachRawArtifacts uploads request/response/extracted results as attachments instead of inlining them.
+	AttachRawArtifacts bool `yaml:"attach-raw-artifacts"`
+	// MaxInlineBytes is the largest payload still inlined when AttachRawArtifacts is set. Defaults to 8KB.
+	MaxInlineBytes int `yaml:"max-inline-bytes"`
 }
 
+// defaultMaxInlineBytes is used when Options.MaxInlineBytes is unset.
+const defaultMaxInlineBytes = 8 * 1024
+
 // New creates a new issue tracker integration client based on options.
 func New(options *Options) (*Integration, error) {
-	tp := jira.BasicAuthTransport{
-		Username: options.Email,
-		Password: options.Token,
+	authOptions := options.Auth
+	if authOptions == nil {
+		authOptions = &auth.Options{Kind: auth.KindAPIToken, Email: options.Email, Token: options.Token}
+	}
+	credential, err := auth.New(authOptions)
+	if err != nil {
+		return nil, fmt.Errorf("could not create jira credential: %s", err)
 	}
-	jiraClient, err := jira.NewClient(tp.Client(), options.URL)
+	httpClient, err := credential.HTTPClient()
+	if err != nil {
+		return nil, fmt.Errorf("could not create jira http client: %s", err)
+	}
+	jiraClient, err := jira.NewClient(httpClient, options.URL)
 	if err != nil {
 		return nil, err
 	}
 	return &Integration{jira: jiraClient, options: options}, nil
 }
 
-// CreateIssue creates an issue in the tracker
-func (i *Integration) CreateIssue(event *output.ResultEvent) error {
+// CreateIssue creates an issue in the tracker, updating an existing one
+// instead of creating a duplicate if UpdateExisting is enabled and a match
+// is found.
+func (i *Integration) CreateIssue(event *output.ResultEvent) (*issues.Result, error) {
 	summary := format.Summary(event)
 
-	issueData := &jira.Issue{
-		Fields: &jira.IssueFields{
-			Assignee:    &jira.User{AccountID: i.options.AccountID},
-			Reporter:    &jira.User{AccountID: i.options.AccountID},
-			Description: jiraFormatDescription(event),
-			Type:        jira.IssueType{Name: i.options.IssueType},
-			Project:     jira.Project{Key: i.options.ProjectName},
-			Summary:     summary,
-		},
-	}
-	_, resp, err := i.jira.Issue.Create(issueData)
+	if i.options.UpdateExisting {
+		existing, err := i.findExistingIssue(summary)
+		if err != nil {
+			return nil, fmt.Errorf("could not search for existing issue: %s", err)
+		}
+		if existing != nil {
+			return i.addOccurrenceComment(existing, event)
+		}
+	}
+
+	fields := &jira.IssueFields{
+		Assignee:    &jira.User{AccountID: i.options.AccountID},
+		Reporter:    &jira.User{AccountID: i.options.AccountID},
+		Description: jiraFormatDescription(event, i.options),
+		Type:        jira.IssueType{Name: i.resolveIssueType(event)},
+		Project:     jira.Project{Key: i.options.ProjectName},
+		Summary:     summary,
+		Labels:      append(append([]string{}, i.options.Labels...), trackedLabel, fingerprintLabel(event)),
+	}
+	if priority := i.resolvePriority(event); priority != "" {
+		fields.Priority = &jira.Priority{Name: priority}
+	}
+	for _, component := range i.options.Components {
+		fields.Components = append(fields.Components, &jira.Component{Name: component})
+	}
+	if len(i.options.CustomFields) > 0 {
+		fields.Unknowns = tcontainer.NewMarshalMap()
+		for name, value := range i.options.CustomFields {
+			fields.Unknowns[name] = value
+		}
+	}
+	issueData := &jira.Issue{Fields: fields}
+	createdIssue, resp, err := i.jira.Issue.Create(issueData)
 	if err != nil {
 		var data string
 		if resp != nil && resp.Body != nil {
 			d, _ := ioutil.ReadAll(resp.Body)
 			data = string(d)
 		}
-		return fmt.Errorf("%s => %s", err, data)
+		return nil, fmt.Errorf("%s => %s", err, data)
+	}
+
+	result := &issues.Result{IssueID: createdIssue.ID}
+	if i.options.AttachRawArtifacts {
+		attachmentIDs, err := i.attachArtifacts(createdIssue.ID, event)
+		if err != nil {
+			return result, fmt.Errorf("could not attach artifacts: %s", err)
+		}
+		result.AttachmentIDs = attachmentIDs
+	}
+	return result, nil
+}
+
+// maxInlineBytes returns the configured MaxInlineBytes or its default.
+func (i *Integration) maxInlineBytes() int {
+	if i.options.MaxInlineBytes > 0 {
+		return i.options.MaxInlineBytes
+	}
+	return defaultMaxInlineBytes
+}
+
+// attachArtifacts uploads the request, response and any extracted results
+// that were too large to inline as attachments on the created issue,
+// returning the attachment IDs Jira assigned them.
+func (i *Integration) attachArtifacts(issueID string, event *output.ResultEvent) ([]string, error) {
+	var attachmentIDs []string
+
+	maxInline := i.maxInlineBytes()
+	artifacts := []struct {
+		name string
+		data string
+	}{
+		{"request.txt", event.Request},
+		{"response.txt", event.Response},
+	}
+	if len(event.ExtractedResults) > 0 {
+		artifacts = append(artifacts, struct {
+			name string
+			data string
+		}{"extracted-results.txt", strings.Join(event.ExtractedResults, "\n")})
+	}
+
+	for _, artifact := range artifacts {
+		if artifact.data == "" || len(artifact.data) <= maxInline {
+			continue
+		}
+		attachments, resp, err := i.jira.Issue.PostAttachment(issueID, strings.NewReader(artifact.data), artifact.name)
+		if err != nil {
+			var data string
+			if resp != nil && resp.Body != nil {
+				d, _ := ioutil.ReadAll(resp.Body)
+				data = string(d)
+			}
+			return attachmentIDs, fmt.Errorf("%s => %s", err, data)
+		}
+		for _, attachment := range *attachments {
+			attachmentIDs = append(attachmentIDs, attachment.ID)
+		}
+	}
+	return attachmentIDs, nil
+}
+
+// resolveIssueType returns the Jira issue type to use for the event, routing
+// through IssueTypeMapping by severity if configured and falling back to the
+// statically configured IssueType otherwise.
+func (i *Integration) resolveIssueType(event *output.ResultEvent) string {
+	if mapped, ok := i.options.IssueTypeMapping[severityFromEvent(event)]; ok {
+		return mapped
+	}
+	return i.options.IssueType
+}
+
+// resolvePriority returns the Jira priority to use for the event, routing
+// through SeverityMapping by severity if configured and falling back to the
+// statically configured Priority otherwise.
+func (i *Integration) resolvePriority(event *output.ResultEvent) string {
+	if mapped, ok := i.options.SeverityMapping[severityFromEvent(event)]; ok {
+		return mapped
+	}
+	return i.options.Priority
+}
+
+// severityFromEvent returns the lowercased severity of the matched template,
+// as stored in the event's template info, or an empty string if unset.
+func severityFromEvent(event *output.ResultEvent) string {
+	if severity, ok := event.Info["severity"]; ok {
+		return strings.ToLower(types.ToString(severity))
+	}
+	return ""
+}
+
+// fingerprint returns a stable identifier for a finding, derived from the
+// template that matched, the host and the matcher name, so the same
+// occurrence can be recognized again across scans regardless of timestamp
+// or extracted results.
+func fingerprint(event *output.ResultEvent) string {
+	hash := sha1.New()
+	hash.Write([]byte(format.GetMatchedTemplate(event)))
+	hash.Write([]byte(event.Host))
+	hash.Write([]byte(event.MatcherName))
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// fingerprintLabel returns the Jira label used to carry a finding's
+// fingerprint on its tracking issue.
+func fingerprintLabel(event *output.ResultEvent) string {
+	return fingerprintLabelPrefix + fingerprint(event)
+}
+
+// SyncState implements issues.Importer. It pages through every issue
+// previously created by nuclei (found by trackedLabel; issues without a
+// fingerprintLabelPrefix label are skipped) and returns the current
+// IssueState of each, keyed by fingerprint, so that
+// the next scan can suppress findings already marked Done/Won't Fix and
+// resurface ones that were reopened. Not yet called anywhere in this tree;
+// see the Importer doc comment.
+func (i *Integration) SyncState(ctx context.Context) (map[string]issues.IssueState, error) {
+	states := make(map[string]issues.IssueState)
+
+	jql := fmt.Sprintf(`project = "%s" AND labels = "%s"`, escapeJQL(i.options.ProjectName), trackedLabel)
+	startAt := 0
+	for {
+		opts := &jira.SearchOptions{StartAt: startAt, MaxResults: 100, Fields: []string{"labels", "status", "resolution"}}
+		page, resp, err := i.jira.Issue.SearchWithContext(ctx, jql, opts)
+		if err != nil {
+			var data string
+			if resp != nil && resp.Body != nil {
+				d, _ := ioutil.ReadAll(resp.Body)
+				data = string(d)
+			}
+			return nil, fmt.Errorf("%s => %s", err, data)
+		}
+		if len(page) == 0 {
+			break
+		}
+		for _, issue := range page {
+			label, ok := fingerprintFromLabels(issue.Fields.Labels)
+			if !ok {
+				continue
+			}
+			states[label] = issueState(issue)
+		}
+		startAt += len(page)
+		if resp == nil || startAt >= resp.Total {
+			break
+		}
+	}
+	return states, nil
+}
+
+// escapeJQL escapes backslashes and double quotes so a value can be safely
+// interpolated into a double-quoted JQL string literal.
+func escapeJQL(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return replacer.Replace(value)
+}
+
+// fingerprintFromLabels extracts the fingerprint carried by a
+// fingerprintLabelPrefix labeled issue, if any.
+func fingerprintFromLabels(labels []string) (string, bool) {
+	for _, label := range labels {
+		if strings.HasPrefix(label, fingerprintLabelPrefix) {
+			return strings.TrimPrefix(label, fingerprintLabelPrefix), true
+		}
 	}
-	return nil
+	return "", false
+}
+
+// issueState classifies a Jira issue into an issues.IssueState based on its
+// status category and resolution.
+func issueState(issue jira.Issue) issues.IssueState {
+	if issue.Fields.Resolution != nil && issue.Fields.Resolution.Name == wontFixResolution {
+		return issues.IssueStateWontFix
+	}
+	if issue.Fields.Status != nil && strings.EqualFold(issue.Fields.Status.StatusCategory.Key, doneStatusCategory) {
+		return issues.IssueStateDone
+	}
+	return issues.IssueStateOpen
+}
+
+// TransitionIssue moves the Jira issue identified by issueID through the
+// named transition (matched case-insensitively), used to reopen an issue
+// whose finding resurfaced after being marked Done/Won't Fix. Like
+// SyncState, it is not called anywhere in this tree. SyncState results are
+// keyed by fingerprint and carry no issue ID, so a caller has to look up the
+// issue ID itself.
+func (i *Integration) TransitionIssue(ctx context.Context, issueID, transitionName string) error {
+	transitions, _, err := i.jira.Issue.GetTransitionsWithContext(ctx, issueID)
+	if err != nil {
+		return fmt.Errorf("could not list transitions: %s", err)
+	}
+	for _, transition := range transitions {
+		if strings.EqualFold(transition.Name, transitionName) {
+			_, err := i.jira.Issue.DoTransitionWithContext(ctx, issueID, transition.ID)
+			if err != nil {
+				return fmt.Errorf("could not transition issue: %s", err)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("transition %q not found for issue %s", transitionName, issueID)
+}
+
+// buildSearchJQL renders the dedupe search JQL for summary, escaping summary
+// and ProjectName so neither can break out of their quoted literals, and
+// filling in the status filter whether the default or a custom
+// SearchJQLTemplate is used.
+func buildSearchJQL(options *Options, summary string) string {
+	statusFilter := options.StatusFilter
+	if len(statusFilter) == 0 {
+		statusFilter = defaultStatusFilter
+	}
+
+	template := options.SearchJQLTemplate
+	if template == "" {
+		template = defaultSearchJQLTemplate
+	}
+	return fmt.Sprintf(template, escapeJQL(options.ProjectName), escapeJQL(summary), strings.Join(quoteStatuses(statusFilter), ", "))
+}
+
+// findExistingIssue searches for an already existing, still open issue for
+// the given finding summary, returning nil if none is found.
+func (i *Integration) findExistingIssue(summary string) (*jira.Issue, error) {
+	jql := buildSearchJQL(i.options, summary)
+
+	issues, resp, err := i.jira.Issue.Search(jql, nil)
+	if err != nil {
+		var data string
+		if resp != nil && resp.Body != nil {
+			d, _ := ioutil.ReadAll(resp.Body)
+			data = string(d)
+		}
+		return nil, fmt.Errorf("%s => %s", err, data)
+	}
+	if len(issues) == 0 {
+		return nil, nil
+	}
+	return &issues[0], nil
+}
+
+// addOccurrenceComment appends a comment recording a new occurrence of a
+// finding to an already existing issue instead of creating a duplicate one.
+func (i *Integration) addOccurrenceComment(issue *jira.Issue, event *output.ResultEvent) (*issues.Result, error) {
+	comment := &jira.Comment{Body: jiraFormatOccurrenceComment(event, i.options)}
+	_, resp, err := i.jira.Issue.AddComment(issue.ID, comment)
+	if err != nil {
+		var data string
+		if resp != nil && resp.Body != nil {
+			d, _ := ioutil.ReadAll(resp.Body)
+			data = string(d)
+		}
+		return nil, fmt.Errorf("%s => %s", err, data)
+	}
+
+	result := &issues.Result{IssueID: issue.ID}
+	if i.options.AttachRawArtifacts {
+		attachmentIDs, err := i.attachArtifacts(issue.ID, event)
+		if err != nil {
+			return result, fmt.Errorf("could not attach artifacts: %s", err)
+		}
+		result.AttachmentIDs = attachmentIDs
+	}
+	return result, nil
+}
+
+// quoteStatuses wraps each status name in quotes so multi-word statuses
+// (e.g. "Won't Fix") are valid within a JQL `in (...)` clause.
+func quoteStatuses(statuses []string) []string {
+	quoted := make([]string, len(statuses))
+	for idx, status := range statuses {
+		quoted[idx] = fmt.Sprintf(`"%s"`, status)
+	}
+	return quoted
+}
+
+// jiraFormatOccurrenceComment formats a comment recording a new occurrence
+// of a finding that already has an open issue tracking it. Like
+// jiraFormatDescription, payloads larger than options.MaxInlineBytes are left
+// out of the comment and replaced with a pointer to the attachment
+// addOccurrenceComment uploads via attachArtifacts when
+// options.AttachRawArtifacts is set.
+func jiraFormatOccurrenceComment(event *output.ResultEvent, options *Options) string {
+	attachRaw := options.AttachRawArtifacts
+	maxInline := options.MaxInlineBytes
+	if maxInline <= 0 {
+		maxInline = defaultMaxInlineBytes
+	}
+
+	builder := &bytes.Buffer{}
+	builder.WriteString("*New occurrence detected*\n\n*Timestamp*: ")
+	builder.WriteString(event.Timestamp.Format("Mon Jan 2 15:04:05 -0700 MST 2006"))
+	writeInlineOrAttachment(builder, "Request", "request.txt", event.Request, attachRaw, maxInline)
+	writeInlineOrAttachment(builder, "Response", "response.txt", event.Response, attachRaw, maxInline)
+
+	if len(event.ExtractedResults) > 0 {
+		extracted := strings.Join(event.ExtractedResults, "\n")
+		if attachRaw && len(extracted) > maxInline {
+			builder.WriteString("\n*Extracted results*: see attachment extracted-results.txt\n")
+		} else {
+			builder.WriteString("\n*Extracted results*:\n\n")
+			for _, v := range event.ExtractedResults {
+				builder.WriteString("- ")
+				builder.WriteString(v)
+				builder.WriteString("\n")
+			}
+		}
+	}
+	return builder.String()
+}
+
+// writeInlineOrAttachment writes value as a Jira {code} block under label, or
+// a pointer to the named attachment when attachRaw is set and value exceeds
+// maxInline, matching the attachment attachArtifacts uploads in that case.
+// Shared by jiraFormatDescription and jiraFormatOccurrenceComment so both
+// respect options.AttachRawArtifacts/MaxInlineBytes identically.
+func writeInlineOrAttachment(builder *bytes.Buffer, label, attachment, value string, attachRaw bool, maxInline int) {
+	if attachRaw && len(value) > maxInline {
+		builder.WriteString("\n*")
+		builder.WriteString(label)
+		builder.WriteString("*: see attachment ")
+		builder.WriteString(attachment)
+		builder.WriteString("\n")
+		return
+	}
+	builder.WriteString("\n*")
+	builder.WriteString(label)
+	builder.WriteString("*\n\n{code}\n")
+	builder.WriteString(value)
+	builder.WriteString("\n{code}\n")
 }
 
 // jiraFormatDescription formats a short description of the generated
-// event by the nuclei scanner in Jira format.
-func jiraFormatDescription(event *output.ResultEvent) string {
+// event by the nuclei scanner in Jira format. When options.AttachRawArtifacts
+// is set, payloads larger than options.MaxInlineBytes are left out of the
+// description and are instead uploaded as issue attachments by CreateIssue.
+func jiraFormatDescription(event *output.ResultEvent, options *Options) string {
 	template := format.GetMatchedTemplate(event)
+	attachRaw := options.AttachRawArtifacts
+	maxInline := options.MaxInlineBytes
+	if maxInline <= 0 {
+		maxInline = defaultMaxInlineBytes
+	}
 
 	builder := &bytes.Buffer{}
 	builder.WriteString("*Details*: *")
@@ -94,22 +525,26 @@ func jiraFormatDescription(event *output.ResultEvent) string {
 	for k, v := range event.Info {
 		builder.WriteString(fmt.Sprintf("| %s | %s |\n", k, v))
 	}
-	builder.WriteString("\n*Request*\n\n{code}\n")
-	builder.WriteString(event.Request)
-	builder.WriteString("\n{code}\n\n*Response*\n\n{code}\n")
-	builder.WriteString(event.Response)
-	builder.WriteString("\n{code}\n\n")
+
+	writeInlineOrAttachment(builder, "Request", "request.txt", event.Request, attachRaw, maxInline)
+	writeInlineOrAttachment(builder, "Response", "response.txt", event.Response, attachRaw, maxInline)
+	builder.WriteString("\n")
 
 	if len(event.ExtractedResults) > 0 || len(event.Metadata) > 0 {
 		builder.WriteString("*Extra Information*\n\n")
 		if len(event.ExtractedResults) > 0 {
-			builder.WriteString("*Extracted results*:\n\n")
-			for _, v := range event.ExtractedResults {
-				builder.WriteString("- ")
-				builder.WriteString(v)
+			extracted := strings.Join(event.ExtractedResults, "\n")
+			if attachRaw && len(extracted) > maxInline {
+				builder.WriteString("*Extracted results*: see attachment extracted-results.txt\n\n")
+			} else {
+				builder.WriteString("*Extracted results*:\n\n")
+				for _, v := range event.ExtractedResults {
+					builder.WriteString("- ")
+					builder.WriteString(v)
+					builder.WriteString("\n")
+				}
 				builder.WriteString("\n")
 			}
-			builder.WriteString("\n")
 		}
 		if len(event.Metadata) > 0 {
 			builder.WriteString("*Metadata*:\n\n")
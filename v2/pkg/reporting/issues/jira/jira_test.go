@@ -0,0 +1,190 @@
+package jira
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/projectdiscovery/nuclei/v2/pkg/output"
+	"github.com/projectdiscovery/nuclei/v2/pkg/reporting/issues"
+)
+
+func TestQuoteStatuses(t *testing.T) {
+	got := quoteStatuses([]string{"Done", "Won't Fix"})
+	want := []string{`"Done"`, `"Won't Fix"`}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for idx := range want {
+		if got[idx] != want[idx] {
+			t.Errorf("index %d: got %s, want %s", idx, got[idx], want[idx])
+		}
+	}
+}
+
+func TestEscapeJQL(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"plain", "example.com", "example.com"},
+		{"quote", `example.com" OR project = "OTHER`, `example.com\" OR project = \"OTHER`},
+		{"backslash", `C:\temp`, `C:\\temp`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := escapeJQL(tt.value); got != tt.want {
+				t.Errorf("escapeJQL(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildSearchJQL(t *testing.T) {
+	options := &Options{ProjectName: `AB"C`}
+	jql := buildSearchJQL(options, `host" OR 1=1`)
+	if want := `project = "AB\"C" AND summary ~ "host\" OR 1=1" AND status not in ("Done")`; jql != want {
+		t.Errorf("buildSearchJQL() = %q, want %q", jql, want)
+	}
+}
+
+func TestBuildSearchJQLCustomTemplateKeepsStatusFilter(t *testing.T) {
+	options := &Options{
+		ProjectName:       "AB",
+		SearchJQLTemplate: `project = "%s" AND text ~ "%s" AND status not in (%s)`,
+		StatusFilter:      []string{"Closed"},
+	}
+	jql := buildSearchJQL(options, "host")
+	if want := `project = "AB" AND text ~ "host" AND status not in ("Closed")`; jql != want {
+		t.Errorf("buildSearchJQL() = %q, want %q", jql, want)
+	}
+}
+
+func TestResolveIssueTypeAndPriority(t *testing.T) {
+	options := &Options{
+		IssueType:        "Task",
+		Priority:         "Medium",
+		IssueTypeMapping: map[string]string{"critical": "Bug"},
+		SeverityMapping:  map[string]string{"critical": "Highest"},
+	}
+	i := &Integration{options: options}
+
+	critical := &output.ResultEvent{Info: map[string]interface{}{"severity": "critical"}}
+	if got := i.resolveIssueType(critical); got != "Bug" {
+		t.Errorf("resolveIssueType(critical) = %s, want Bug", got)
+	}
+	if got := i.resolvePriority(critical); got != "Highest" {
+		t.Errorf("resolvePriority(critical) = %s, want Highest", got)
+	}
+
+	info := &output.ResultEvent{Info: map[string]interface{}{"severity": "info"}}
+	if got := i.resolveIssueType(info); got != "Task" {
+		t.Errorf("resolveIssueType(info) = %s, want Task", got)
+	}
+	if got := i.resolvePriority(info); got != "Medium" {
+		t.Errorf("resolvePriority(info) = %s, want Medium", got)
+	}
+}
+
+func TestIssueState(t *testing.T) {
+	tests := []struct {
+		name  string
+		issue jira.Issue
+		want  issues.IssueState
+	}{
+		{
+			name:  "open",
+			issue: jira.Issue{Fields: &jira.IssueFields{Status: &jira.Status{StatusCategory: jira.StatusCategory{Key: "new"}}}},
+			want:  issues.IssueStateOpen,
+		},
+		{
+			name:  "done",
+			issue: jira.Issue{Fields: &jira.IssueFields{Status: &jira.Status{StatusCategory: jira.StatusCategory{Key: "done"}}}},
+			want:  issues.IssueStateDone,
+		},
+		{
+			name: "wont fix takes priority over status",
+			issue: jira.Issue{Fields: &jira.IssueFields{
+				Status:     &jira.Status{StatusCategory: jira.StatusCategory{Key: "new"}},
+				Resolution: &jira.Resolution{Name: wontFixResolution},
+			}},
+			want: issues.IssueStateWontFix,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := issueState(tt.issue); got != tt.want {
+				t.Errorf("issueState() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFingerprintFromLabels(t *testing.T) {
+	labels := []string{trackedLabel, fingerprintLabelPrefix + "abc123"}
+	got, ok := fingerprintFromLabels(labels)
+	if !ok || got != "abc123" {
+		t.Errorf("fingerprintFromLabels() = (%s, %v), want (abc123, true)", got, ok)
+	}
+
+	if _, ok := fingerprintFromLabels([]string{trackedLabel}); ok {
+		t.Errorf("fingerprintFromLabels() found a fingerprint where there was none")
+	}
+}
+
+func TestFingerprintStable(t *testing.T) {
+	event := &output.ResultEvent{Host: "https://example.com", MatcherName: "match-1"}
+
+	first := fingerprint(event)
+	second := fingerprint(event)
+	if first != second {
+		t.Errorf("fingerprint() is not stable: %s != %s", first, second)
+	}
+
+	other := &output.ResultEvent{Host: "https://other.com", MatcherName: "match-1"}
+	if fingerprint(other) == first {
+		t.Errorf("fingerprint() collided across different hosts")
+	}
+}
+
+func TestJiraFormatDescriptionAttachesLargePayloads(t *testing.T) {
+	options := &Options{AttachRawArtifacts: true, MaxInlineBytes: 10}
+	event := &output.ResultEvent{
+		Timestamp: time.Now(),
+		Request:   "this request body is longer than ten bytes",
+		Response:  "ok",
+	}
+
+	description := jiraFormatDescription(event, options)
+	if want := "see attachment request.txt"; !strings.Contains(description, want) {
+		t.Errorf("description should reference the request attachment, got: %s", description)
+	}
+	if strings.Contains(description, event.Request) {
+		t.Errorf("oversized request should not be inlined in the description")
+	}
+	if !strings.Contains(description, event.Response) {
+		t.Errorf("response under MaxInlineBytes should still be inlined")
+	}
+}
+
+func TestJiraFormatOccurrenceCommentAttachesLargePayloads(t *testing.T) {
+	options := &Options{AttachRawArtifacts: true, MaxInlineBytes: 10}
+	event := &output.ResultEvent{
+		Timestamp: time.Now(),
+		Request:   "this request body is longer than ten bytes",
+		Response:  "ok",
+	}
+
+	comment := jiraFormatOccurrenceComment(event, options)
+	if want := "see attachment request.txt"; !strings.Contains(comment, want) {
+		t.Errorf("comment should reference the request attachment, got: %s", comment)
+	}
+	if strings.Contains(comment, event.Request) {
+		t.Errorf("oversized request should not be inlined in the occurrence comment")
+	}
+	if !strings.Contains(comment, event.Response) {
+		t.Errorf("response under MaxInlineBytes should still be inlined")
+	}
+}